@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"sort"
+	"sync"
 	"time"
 
 	flag "github.com/spf13/pflag"
@@ -12,50 +14,85 @@ import (
 
 // Config stores all configuration options for the dkron package.
 type Config struct {
-	NodeName              string `mapstructure:"node-name"`
-	BindAddr              string `mapstructure:"bind-addr"`
-	HTTPAddr              string `mapstructure:"http-addr"`
-	Discover              string
-	Backend               string
-	BackendMachines       []string `mapstructure:"backend-machine"`
-	Profile               string
-	Interface             string
-	AdvertiseAddr         string            `mapstructure:"advertise-addr"`
+	// reloadMu guards the fields that Reload is allowed to mutate at runtime.
+	reloadMu sync.Mutex
+	// httpCertManager and rpcCertManager, once set by HTTPTLSConfig/RPCTLSConfig,
+	// are refreshed on SIGHUP so certificate rotation takes effect without
+	// dropping existing connections.
+	httpCertManager *certManager
+	rpcCertManager  *certManager
+
+	ConfigFile string `mapstructure:"config-file" description:"path to a JSON, YAML or HCL config file; values are overridden by any flag or env var set explicitly"`
+	NodeName   string `mapstructure:"node-name" description:"node name"`
+	BindAddr   string `mapstructure:"bind-addr" description:"address to bind listeners to"`
+	HTTPAddr   string `mapstructure:"http-addr" description:"HTTP address"`
+	// Discovers is the set of cluster discovery providers to union addresses
+	// from before joining Serf. Populated from the repeatable --discover
+	// flag, which is registered by hand since its value isn't a single
+	// reflectable type; see ParseDiscoverSpec and the discovery package.
+	Discovers []DiscoverSpec
+
+	Backend         string   `description:"store backend"`
+	BackendMachines []string `mapstructure:"backend-machine" description:"store backend machines addresses"`
+	Profile         string   `description:"timing profile to use (lan, wan, local)"`
+	Interface       string   `mapstructure:"interface" description:"network interface to use for addresses in serf"`
+	AdvertiseAddr   string   `mapstructure:"advertise-addr" description:"address to advertise to other nodes"`
+	// Tags has no description tag, and so is not auto-registered as a flag by
+	// registerConfigFlags: --tag must stay a repeatable StringSlice for
+	// backward compatibility (see ConfigFlagSet), which the reflective
+	// registrar can't express for a map[string]string field.
 	Tags                  map[string]string `mapstructure:"tags"`
-	SnapshotPath          string            `mapstructure:"snapshot-path"`
-	ReconnectInterval     time.Duration     `mapstructure:"reconnect-interval"`
-	ReconnectTimeout      time.Duration     `mapstructure:"reconnect-timeout"`
-	TombstoneTimeout      time.Duration     `mapstructure:"tombstone-timeout"`
-	DisableNameResolution bool              `mapstructure:"disable-name-resolution"`
-	KeyringFile           string            `mapstructure:"keyring-file"`
-	RejoinAfterLeave      bool              `mapstructure:"rejoin-after-leave"`
-	Server                bool
-	EncryptKey            string   `mapstructure:"encrypt-key"`
-	StartJoin             []string `mapstructure:"start-join"`
-	Keyspace              string
-	RPCPort               int    `mapstructure:"rpc-port"`
-	AdvertiseRPCPort      int    `mapstructure:"advertise-rpc-port"`
-	LogLevel              string `mapstructure:"log-level"`
-
-	MailHost          string `mapstructure:"mail-host"`
-	MailPort          uint16 `mapstructure:"mail-port"`
-	MailUsername      string `mapstructure:"mail-username"`
-	MailPassword      string `mapstructure:"mail-password"`
-	MailFrom          string `mapstructure:"mail-from"`
-	MailPayload       string `mapstructure:"mail-payload"`
-	MailSubjectPrefix string `mapstructure:"mail-subject-prefix"`
-
-	WebhookURL     string   `mapstructure:"webhook-url"`
-	WebhookPayload string   `mapstructure:"webhook-payload"`
-	WebhookHeaders []string `mapstructure:"webhook-headers"`
+	SnapshotPath          string            `mapstructure:"snapshot-path" description:"path to the snapshot file used to recover from a crash"`
+	ReconnectInterval     time.Duration     `mapstructure:"reconnect-interval" description:"interval to attempt to reconnect to failed nodes"`
+	ReconnectTimeout      time.Duration     `mapstructure:"reconnect-timeout" description:"time after which a failed node is reaped from the member list"`
+	TombstoneTimeout      time.Duration     `mapstructure:"tombstone-timeout" description:"time after which a left node is reaped from the member list"`
+	DisableNameResolution bool              `mapstructure:"disable-name-resolution" description:"disable reverse DNS lookups for node addresses"`
+	KeyringFile           string            `mapstructure:"keyring-file" description:"path to a file containing the Serf encryption keyring"`
+	RejoinAfterLeave      bool              `mapstructure:"rejoin-after-leave" description:"rejoin the cluster using the persisted state after a previous leave"`
+	Server                bool              `description:"start dkron server"`
+	EncryptKey            string            `mapstructure:"encrypt-key" flag:"encrypt" description:"encryption key"`
+	StartJoin             []string          `mapstructure:"start-join" flag:"join" description:"address of agent to join on startup"`
+	Keyspace              string            `description:"key namespace to use"`
+	RPCPort               int               `mapstructure:"rpc-port" description:"RPC port"`
+	AdvertiseRPCPort      int               `mapstructure:"advertise-rpc-port" description:"advertise RPC port"`
+	LogLevel              string            `mapstructure:"log-level" description:"Log level (debug, info, warn, error, fatal, panic), defaults to info"`
+
+	HTTPCertFile     string `mapstructure:"http-cert-file" description:"path to a certificate to serve HTTPS with"`
+	HTTPKeyFile      string `mapstructure:"http-key-file" description:"path to the private key for http-cert-file"`
+	HTTPClientCAFile string `mapstructure:"http-client-ca-file" description:"path to a CA bundle used to verify HTTPS client certificates (enables mTLS)"`
+	RPCCertFile      string `mapstructure:"rpc-cert-file" description:"path to a certificate to serve RPC with"`
+	RPCKeyFile       string `mapstructure:"rpc-key-file" description:"path to the private key for rpc-cert-file"`
+	RPCClientCAFile  string `mapstructure:"rpc-client-ca-file" description:"path to a CA bundle used to verify RPC client certificates (enables mTLS)"`
+
+	MailHost          string `mapstructure:"mail-host" description:"notification mail server host"`
+	MailPort          uint16 `mapstructure:"mail-port" description:"port to use for the mail server"`
+	MailUsername      string `mapstructure:"mail-username" description:"username for the mail server"`
+	MailPassword      string `mapstructure:"mail-password" description:"password of the mail server"`
+	MailFrom          string `mapstructure:"mail-from" description:"notification emails from address"`
+	MailPayload       string `mapstructure:"mail-payload" description:"notification mail payload"`
+	MailSubjectPrefix string `mapstructure:"mail-subject-prefix" description:"notification mail subject prefix"`
+	// MailUseTLS connects to MailHost over implicit TLS (SMTPS), typically port 465.
+	MailUseTLS bool `mapstructure:"mail-use-tls" description:"connect to the mail server using implicit TLS (SMTPS)"`
+	// MailUseStartTLS upgrades a plaintext connection with STARTTLS before authenticating.
+	MailUseStartTLS bool `mapstructure:"mail-use-starttls" description:"upgrade the connection to the mail server with STARTTLS before authenticating"`
+	// MailAuthMechanism selects the SMTP AUTH mechanism: "plain", "login", "cram-md5" or "none".
+	MailAuthMechanism string `mapstructure:"mail-auth-mechanism" description:"SMTP AUTH mechanism to use (plain, login, cram-md5, none)"`
+	// MailSkipVerify disables server certificate verification, for testing against self-signed relays.
+	MailSkipVerify bool `mapstructure:"mail-skip-verify" description:"skip mail server certificate verification"`
+	// MailRootCAFile is an additional CA bundle to trust when verifying MailHost's certificate.
+	MailRootCAFile string `mapstructure:"mail-root-ca-file" description:"additional CA bundle to trust for the mail server certificate"`
+
+	WebhookURL     string   `mapstructure:"webhook-url" description:"notification webhook url"`
+	WebhookPayload string   `mapstructure:"webhook-payload" description:"notification webhook payload"`
+	WebhookHeaders []string `mapstructure:"webhook-headers" flag:"webhook-header" description:"notification webhook additional header"`
 
 	// DogStatsdAddr is the address of a dogstatsd instance. If provided,
 	// metrics will be sent to that instance
-	DogStatsdAddr string `mapstructure:"dog-statsd-addr"`
+	DogStatsdAddr string `mapstructure:"dog-statsd-addr" description:"DataDog Agent address"`
 	// DogStatsdTags are the global tags that should be sent with each packet to dogstatsd
 	// It is a list of strings, where each string looks like "my_tag_name:my_tag_value"
-	DogStatsdTags []string `mapstructure:"dog-statsd-tags"`
-	StatsdAddr    string   `mapstructure:"statsd-addr"`
+	DogStatsdTags []string `mapstructure:"dog-statsd-tags" description:"Datadog tags, specified as key:value"`
+	StatsdAddr    string   `mapstructure:"statsd-addr" description:"Statsd Address"`
 }
 
 // DefaultBindPort is the default port that dkron will use for Serf communication
@@ -73,7 +110,7 @@ func DefaultConfig() *Config {
 		NodeName:          hostname,
 		BindAddr:          fmt.Sprintf("0.0.0.0:%d", DefaultBindPort),
 		HTTPAddr:          ":8080",
-		Discover:          "dkron",
+		Discovers:         []DiscoverSpec{{Provider: "mdns", Args: map[string]string{"name": "dkron"}}},
 		Backend:           "etcd",
 		BackendMachines:   []string{"127.0.0.1:2379"},
 		Profile:           "lan",
@@ -81,50 +118,53 @@ func DefaultConfig() *Config {
 		LogLevel:          "info",
 		RPCPort:           6868,
 		MailSubjectPrefix: "[Dkron]",
+		MailAuthMechanism: "plain",
 		Tags:              tags,
 	}
 }
 
-// configFlagSet creates all of our configuration flags.
-func ConfigFlagSet() *flag.FlagSet {
-	c := DefaultConfig()
+// ConfigFlagSet creates all of our configuration flags by reflecting over
+// config; see registerConfigFlags for how fields become flags. Flag defaults
+// come from config, so callers that want a config file's values to show up
+// as flag defaults (see LoadConfig) should pass in a config already merged
+// with the file rather than a bare DefaultConfig(). The returned []FlagInfo
+// is every reflectively-registered flag's name/env var/type/description; it
+// must be fed to ApplyEnvOverrides after Parse to bind each flag's
+// environment variable, and is what HelpJSON serializes for --help-json.
+//
+// A few flags are still registered by hand because they don't map onto a
+// single reflectable Config field, and so are absent from the returned
+// []FlagInfo (ApplyEnvOverrides has nothing to bind them to an env var with):
+//   - --discover, because Config.Discovers isn't a type the reflective
+//     registrar can represent directly.
+//   - --tag, because it must stay a repeatable StringSlice of "key=value"
+//     pairs for existing callers doing cmdFlags.GetStringSlice("tag"); a
+//     naive reflection over Config.Tags (map[string]string) would silently
+//     turn it into a StringToString flag instead.
+//   - --help-json, which has no backing Config field at all: see HelpJSON
+//     and PrintHelpJSON.
+func ConfigFlagSet(config *Config) (*flag.FlagSet, []FlagInfo) {
 	cmdFlags := flag.NewFlagSet("agent flagset", flag.ContinueOnError)
 
-	cmdFlags.Bool("server", false, "start dkron server")
-	cmdFlags.String("node-name", c.NodeName, "node name")
-	cmdFlags.String("bind-addr", c.BindAddr, "address to bind listeners to")
-	cmdFlags.String("advertise-addr", "", "address to advertise to other nodes")
-	cmdFlags.String("http-addr", c.HTTPAddr, "HTTP address")
-	cmdFlags.String("discover", c.Discover, "mDNS discovery name")
-	cmdFlags.String("backend", c.Backend, "store backend")
-	cmdFlags.StringSlice("backend-machine", c.BackendMachines, "store backend machines addresses")
-	cmdFlags.String("profile", c.Profile, "timing profile to use (lan, wan, local)")
-	cmdFlags.StringSlice("join", []string{}, "address of agent to join on startup")
-	cmdFlags.StringSlice("tag", []string{}, "tag pair, specified as key=value")
-	cmdFlags.String("keyspace", c.Keyspace, "key namespace to use")
-	cmdFlags.String("encrypt", "", "encryption key")
-	cmdFlags.String("log-level", c.LogLevel, "Log level (debug, info, warn, error, fatal, panic), defaults to info")
-	cmdFlags.Int("rpc-port", c.RPCPort, "RPC port")
-	cmdFlags.Int("advertise-rpc-port", 0, "advertise RPC port")
-
-	// Notifications
-	cmdFlags.String("mail-host", "", "notification mail server host")
-	cmdFlags.String("mail-port", "", "port to use for the mail server")
-	cmdFlags.String("mail-username", "", "username for the mail server")
-	cmdFlags.String("mail-password", "", "password of the mail server")
-	cmdFlags.String("mail-from", "", "notification emails from address")
-	cmdFlags.String("mail-payload", "", "notification mail payload")
-	cmdFlags.String("mail-subject-prefix", c.MailSubjectPrefix, "notification mail subject prefix")
-
-	cmdFlags.String("webhook-url", "", "notification webhook url")
-	cmdFlags.String("webhook-payload", "", "notification webhook payload")
-	cmdFlags.StringSlice("webhook-header", []string{}, "notification webhook additional header")
-
-	cmdFlags.String("dog-statsd-addr", "", "DataDog Agent address")
-	cmdFlags.StringSlice("dog-statsd-tags", []string{}, "Datadog tags, specified as key:value")
-	cmdFlags.String("statsd-addr", "", "Statsd Address")
-
-	return cmdFlags
+	infos := registerConfigFlags(cmdFlags, config)
+
+	cmdFlags.StringArray("discover", nil, "cluster discovery provider, specified as provider=key1=val1,key2=val2; may be repeated")
+	cmdFlags.StringSlice("tag", tagsToFlagDefault(config.Tags), "tag pair, specified as key=value")
+	cmdFlags.Bool("help-json", false, "print machine-readable flag documentation as JSON and exit")
+
+	return cmdFlags, infos
+}
+
+// tagsToFlagDefault renders tags as sorted "key=value" pairs, the inverse of
+// how --tag values are parsed, so a config file's tags show up as the --tag
+// flag's default.
+func tagsToFlagDefault(tags map[string]string) []string {
+	pairs := make([]string, 0, len(tags))
+	for k, v := range tags {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return pairs
 }
 
 // AddrParts returns the parts of the BindAddr that should be
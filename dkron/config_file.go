@@ -0,0 +1,324 @@
+package dkron
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/hashicorp/hcl"
+	"github.com/mitchellh/mapstructure"
+)
+
+// ReadConfigFile parses a JSON, YAML or HCL file at path into a Config. The
+// format is inferred from the file extension (.json, .yml, .yaml or .hcl);
+// any other extension is parsed as HCL, since HCL is a superset of JSON.
+//
+// The returned Config only has the fields set that were present in the
+// file; callers are expected to merge it on top of DefaultConfig or, for a
+// reload, onto the running Config (see mergeReloadable). The second return
+// value is the set of top-level keys (lowercased) that were actually present
+// in the file, so a caller can tell a field that was absent apart from one
+// explicitly set to its zero value.
+func ReadConfigFile(path string) (*Config, map[string]bool, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("config file: %w", err)
+	}
+
+	var input map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		if err := hcl.Unmarshal(raw, &input); err != nil {
+			return nil, nil, fmt.Errorf("config file: error parsing JSON: %w", err)
+		}
+	case ".yml", ".yaml":
+		jsonRaw, err := yaml.YAMLToJSON(raw)
+		if err != nil {
+			return nil, nil, fmt.Errorf("config file: error parsing YAML: %w", err)
+		}
+		if err := hcl.Unmarshal(jsonRaw, &input); err != nil {
+			return nil, nil, fmt.Errorf("config file: error parsing YAML: %w", err)
+		}
+	default:
+		if err := hcl.Unmarshal(raw, &input); err != nil {
+			return nil, nil, fmt.Errorf("config file: error parsing HCL: %w", err)
+		}
+	}
+
+	config := &Config{}
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToSliceHookFunc(","),
+		),
+		WeaklyTypedInput: true,
+		Result:           config,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("config file: %w", err)
+	}
+	if err := decoder.Decode(input); err != nil {
+		return nil, nil, fmt.Errorf("config file: error decoding %s: %w", path, err)
+	}
+
+	present := make(map[string]bool, len(input))
+	for k := range input {
+		present[strings.ToLower(k)] = true
+	}
+
+	return config, present, nil
+}
+
+// applyConfigFile overlays every field fileConfig's source file actually set
+// (per present) onto config in place, covering every Config field rather
+// than just the reloadable subset mergeReloadable restricts itself to: at
+// startup a config file is allowed to set anything, including options like
+// BindAddr or Server that can never change via a later SIGHUP reload. It is
+// the "defaults -> file" step of LoadConfig's resolution order. config is
+// mutated directly rather than copied, since Config embeds a sync.Mutex that
+// must not be copied; callers that need the previous value untouched should
+// pass a config they own exclusively, as LoadConfig does with a freshly
+// built DefaultConfig().
+func applyConfigFile(config, fileConfig *Config, present map[string]bool) *Config {
+	cv := reflect.ValueOf(config).Elem()
+	fv := reflect.ValueOf(fileConfig).Elem()
+	t := cv.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue // unexported
+		}
+
+		// An untagged field (e.g. Server) is decoded by mapstructure from its
+		// field name, matched case-insensitively against the file's keys.
+		key := field.Tag.Get("mapstructure")
+		if key == "" {
+			key = strings.ToLower(field.Name)
+		}
+		if !present[key] {
+			continue
+		}
+
+		cv.Field(i).Set(fv.Field(i))
+	}
+
+	return config
+}
+
+// reloadableFields are the Config fields Reload is allowed to change on a
+// running agent. Anything that affects how the node binds to the network,
+// joins the cluster or switches mode requires a restart instead. The keys
+// are the field's mapstructure tag, matching the keys in the `present` map
+// ReadConfigFile returns.
+var reloadableFields = []string{
+	"log-level",
+	"mail-host",
+	"mail-port",
+	"mail-username",
+	"mail-password",
+	"mail-from",
+	"mail-payload",
+	"mail-subject-prefix",
+	"mail-use-tls",
+	"mail-use-starttls",
+	"mail-auth-mechanism",
+	"mail-skip-verify",
+	"mail-root-ca-file",
+	"webhook-url",
+	"webhook-payload",
+	"webhook-headers",
+	"tags",
+	"dog-statsd-tags",
+	"keyring-file",
+}
+
+// mergeReloadable builds the Config that should be handed to Reload out of a
+// sparse, freshly re-read fileConfig: every reloadable field present in the
+// file (per present) takes fileConfig's value, and every reloadable field
+// absent from the file keeps c's current live value. Without this step, a
+// config file that only sets e.g. mail-host would blank out every other
+// reloadable field that had been set some other way (a flag, an env var, or
+// a previous reload) the moment Reload applied it.
+func (c *Config) mergeReloadable(fileConfig *Config, present map[string]bool) *Config {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+
+	merged := &Config{}
+
+	pick := func(key string, fromFile, fromLive interface{}) interface{} {
+		if present[key] {
+			return fromFile
+		}
+		return fromLive
+	}
+
+	merged.LogLevel = pick("log-level", fileConfig.LogLevel, c.LogLevel).(string)
+
+	merged.MailHost = pick("mail-host", fileConfig.MailHost, c.MailHost).(string)
+	merged.MailPort = pick("mail-port", fileConfig.MailPort, c.MailPort).(uint16)
+	merged.MailUsername = pick("mail-username", fileConfig.MailUsername, c.MailUsername).(string)
+	merged.MailPassword = pick("mail-password", fileConfig.MailPassword, c.MailPassword).(string)
+	merged.MailFrom = pick("mail-from", fileConfig.MailFrom, c.MailFrom).(string)
+	merged.MailPayload = pick("mail-payload", fileConfig.MailPayload, c.MailPayload).(string)
+	merged.MailSubjectPrefix = pick("mail-subject-prefix", fileConfig.MailSubjectPrefix, c.MailSubjectPrefix).(string)
+	merged.MailUseTLS = pick("mail-use-tls", fileConfig.MailUseTLS, c.MailUseTLS).(bool)
+	merged.MailUseStartTLS = pick("mail-use-starttls", fileConfig.MailUseStartTLS, c.MailUseStartTLS).(bool)
+	merged.MailAuthMechanism = pick("mail-auth-mechanism", fileConfig.MailAuthMechanism, c.MailAuthMechanism).(string)
+	merged.MailSkipVerify = pick("mail-skip-verify", fileConfig.MailSkipVerify, c.MailSkipVerify).(bool)
+	merged.MailRootCAFile = pick("mail-root-ca-file", fileConfig.MailRootCAFile, c.MailRootCAFile).(string)
+
+	merged.WebhookURL = pick("webhook-url", fileConfig.WebhookURL, c.WebhookURL).(string)
+	merged.WebhookPayload = pick("webhook-payload", fileConfig.WebhookPayload, c.WebhookPayload).(string)
+	merged.WebhookHeaders = pick("webhook-headers", fileConfig.WebhookHeaders, c.WebhookHeaders).([]string)
+
+	merged.Tags = pick("tags", fileConfig.Tags, c.Tags).(map[string]string)
+	merged.DogStatsdTags = pick("dog-statsd-tags", fileConfig.DogStatsdTags, c.DogStatsdTags).([]string)
+
+	merged.KeyringFile = pick("keyring-file", fileConfig.KeyringFile, c.KeyringFile).(string)
+
+	return merged
+}
+
+// mailSettings is a point-in-time snapshot of the mail-related fields
+// Reload is allowed to change at runtime. MailNotifier takes one via
+// Config.mailSettings instead of reading fields off *Config directly, so a
+// Send in flight can't observe a torn mix of old and new values (or race the
+// Go memory model outright) against a concurrent SIGHUP reload.
+type mailSettings struct {
+	host, from, username, password string
+	port                           uint16
+	useTLS, useStartTLS            bool
+	skipVerify                     bool
+	authMechanism                  string
+	rootCAFile                     string
+}
+
+// mailSettings takes a consistent snapshot of c's mail-related fields under
+// reloadMu, safe to read freely afterwards since it is a private copy.
+func (c *Config) mailSettings() mailSettings {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+
+	return mailSettings{
+		host:          c.MailHost,
+		port:          c.MailPort,
+		username:      c.MailUsername,
+		password:      c.MailPassword,
+		from:          c.MailFrom,
+		useTLS:        c.MailUseTLS,
+		useStartTLS:   c.MailUseStartTLS,
+		authMechanism: c.MailAuthMechanism,
+		skipVerify:    c.MailSkipVerify,
+		rootCAFile:    c.MailRootCAFile,
+	}
+}
+
+// Reload applies the reloadable subset of new onto c in place. Each field is
+// validated independently: a field that fails validation is reported in the
+// returned error but leaves c's current value for that field untouched,
+// while every other field that validates is still applied. Reload only
+// returns a nil error when every reloadable field applied cleanly, but a
+// non-nil error does not mean nothing changed — check the error text (or
+// re-read the relevant field) to see which ones were rejected.
+func (c *Config) Reload(new *Config) error {
+	if new == nil {
+		return fmt.Errorf("reload: nil config")
+	}
+
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+
+	var errs []string
+
+	if err := validateLogLevel(new.LogLevel); err != nil {
+		errs = append(errs, fmt.Sprintf("log-level: %s", err))
+	} else {
+		c.LogLevel = new.LogLevel
+	}
+
+	if err := validateMailAuthMechanism(new.MailAuthMechanism); err != nil {
+		errs = append(errs, fmt.Sprintf("mail-auth-mechanism: %s", err))
+	} else {
+		c.MailAuthMechanism = new.MailAuthMechanism
+	}
+
+	if err := validateKeyringFile(new.KeyringFile); err != nil {
+		errs = append(errs, fmt.Sprintf("keyring-file: %s", err))
+	} else {
+		c.KeyringFile = new.KeyringFile
+	}
+
+	if err := validateMailRootCAFile(new.MailRootCAFile); err != nil {
+		errs = append(errs, fmt.Sprintf("mail-root-ca-file: %s", err))
+	} else {
+		c.MailRootCAFile = new.MailRootCAFile
+	}
+
+	// The remaining reloadable fields have no validity constraint beyond
+	// "it decoded into the right Go type", which mapstructure already
+	// guarantees, so they're applied unconditionally.
+	c.MailHost = new.MailHost
+	c.MailPort = new.MailPort
+	c.MailUsername = new.MailUsername
+	c.MailPassword = new.MailPassword
+	c.MailFrom = new.MailFrom
+	c.MailPayload = new.MailPayload
+	c.MailSubjectPrefix = new.MailSubjectPrefix
+	c.MailUseTLS = new.MailUseTLS
+	c.MailUseStartTLS = new.MailUseStartTLS
+	c.MailSkipVerify = new.MailSkipVerify
+
+	c.WebhookURL = new.WebhookURL
+	c.WebhookPayload = new.WebhookPayload
+	c.WebhookHeaders = new.WebhookHeaders
+
+	c.Tags = new.Tags
+	c.DogStatsdTags = new.DogStatsdTags
+
+	if len(errs) > 0 {
+		return fmt.Errorf("reload: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func validateLogLevel(level string) error {
+	switch strings.ToLower(level) {
+	case "debug", "info", "warn", "error", "fatal", "panic":
+		return nil
+	default:
+		return fmt.Errorf("invalid log level %q", level)
+	}
+}
+
+func validateMailAuthMechanism(mechanism string) error {
+	switch strings.ToLower(mechanism) {
+	case "", "plain", "login", "cram-md5", "none":
+		return nil
+	default:
+		return fmt.Errorf("invalid mail auth mechanism %q", mechanism)
+	}
+}
+
+func validateKeyringFile(path string) error {
+	if path == "" {
+		return nil
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("error reading keyring file: %w", err)
+	}
+	return nil
+}
+
+func validateMailRootCAFile(path string) error {
+	if path == "" {
+		return nil
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("error reading mail root CA file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package dkron
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+)
+
+// LoadConfig builds the Config an agent should run with, resolving options
+// in the order documented throughout this package: defaults -> file ->
+// flags -> env. argv is typically os.Args[1:].
+//
+//  1. DefaultConfig() seeds every field.
+//  2. If --config-file (or DKRON_CONFIG_FILE) names a file, it is read with
+//     ReadConfigFile and layered on top via applyConfigFile.
+//  3. The flags built by ConfigFlagSet, with the result of (1)+(2) as their
+//     defaults, are parsed from argv.
+//  4. ApplyEnvOverrides applies any bound environment variable, taking
+//     precedence over an explicit flag.
+func LoadConfig(argv []string) (*Config, error) {
+	configFile, err := peekConfigFile(argv)
+	if err != nil {
+		return nil, err
+	}
+	// The env var beats the flag here too, matching ApplyEnvOverrides below.
+	if env, ok := os.LookupEnv(envVarName("config-file")); ok {
+		configFile = env
+	}
+
+	config := DefaultConfig()
+	if configFile != "" {
+		fileConfig, present, err := ReadConfigFile(configFile)
+		if err != nil {
+			return nil, err
+		}
+		config = applyConfigFile(config, fileConfig, present)
+		config.ConfigFile = configFile
+	}
+
+	cmdFlags, infos := ConfigFlagSet(config)
+	if err := cmdFlags.Parse(argv); err != nil {
+		return nil, err
+	}
+
+	if err := ApplyEnvOverrides(cmdFlags, infos); err != nil {
+		return nil, err
+	}
+
+	if err := populateConfigFromFlags(cmdFlags, config); err != nil {
+		return nil, err
+	}
+
+	if f := cmdFlags.Lookup("tag"); f != nil && f.Changed {
+		raw, err := cmdFlags.GetStringSlice("tag")
+		if err != nil {
+			return nil, err
+		}
+		tags, err := parseTags(raw)
+		if err != nil {
+			return nil, err
+		}
+		config.Tags = tags
+	}
+
+	if f := cmdFlags.Lookup("discover"); f != nil && f.Changed {
+		raw, err := cmdFlags.GetStringArray("discover")
+		if err != nil {
+			return nil, err
+		}
+		if err := config.ParseDiscoverSpecs(raw); err != nil {
+			return nil, err
+		}
+	}
+
+	return config, nil
+}
+
+// peekConfigFile extracts --config-file's value from argv, if present,
+// without erroring on any other flag: the full flag set isn't built yet at
+// this point, since its defaults depend on the config file we're looking
+// for here.
+func peekConfigFile(argv []string) (string, error) {
+	fs := flag.NewFlagSet("peek-config-file", flag.ContinueOnError)
+	fs.ParseErrorsWhitelist = flag.ParseErrorsWhitelist{UnknownFlags: true}
+	fs.Usage = func() {}
+
+	path := fs.String("config-file", "", "")
+	if err := fs.Parse(argv); err != nil {
+		return "", fmt.Errorf("config: error scanning for --config-file: %w", err)
+	}
+
+	return *path, nil
+}
+
+// parseTags parses --tag's repeated "key=value" values into a tags map, the
+// inverse of tagsToFlagDefault.
+func parseTags(raw []string) (map[string]string, error) {
+	tags := make(map[string]string, len(raw))
+	for _, pair := range raw {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("config: tag %q is not of the form key=value", pair)
+		}
+		tags[k] = v
+	}
+	return tags, nil
+}
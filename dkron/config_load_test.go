@@ -0,0 +1,78 @@
+package dkron
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfigDefaultsOnly(t *testing.T) {
+	config, err := LoadConfig(nil)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if config.LogLevel != "info" {
+		t.Errorf("LogLevel = %q, want %q", config.LogLevel, "info")
+	}
+}
+
+func TestLoadConfigFileUnderliesFlags(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dkron.json")
+	if err := os.WriteFile(path, []byte(`{"log-level": "debug", "mail-host": "file.example.com"}`), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	config, err := LoadConfig([]string{"--config-file=" + path})
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	if config.LogLevel != "debug" {
+		t.Errorf("LogLevel = %q, want %q (from config file)", config.LogLevel, "debug")
+	}
+	if config.MailHost != "file.example.com" {
+		t.Errorf("MailHost = %q, want %q (from config file)", config.MailHost, "file.example.com")
+	}
+}
+
+func TestLoadConfigFlagOverridesFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dkron.json")
+	if err := os.WriteFile(path, []byte(`{"log-level": "debug"}`), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	config, err := LoadConfig([]string{"--config-file=" + path, "--log-level=warn"})
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	if config.LogLevel != "warn" {
+		t.Errorf("LogLevel = %q, want %q (flag should beat file)", config.LogLevel, "warn")
+	}
+}
+
+func TestLoadConfigEnvOverridesFlag(t *testing.T) {
+	t.Setenv("DKRON_LOG_LEVEL", "error")
+
+	config, err := LoadConfig([]string{"--log-level=warn"})
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	if config.LogLevel != "error" {
+		t.Errorf("LogLevel = %q, want %q (env should beat flag)", config.LogLevel, "error")
+	}
+}
+
+func TestLoadConfigTagFlag(t *testing.T) {
+	config, err := LoadConfig([]string{"--tag=role=worker", "--tag=dc=us-east"})
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	if config.Tags["role"] != "worker" || config.Tags["dc"] != "us-east" {
+		t.Errorf("Tags = %v, want role=worker and dc=us-east", config.Tags)
+	}
+}
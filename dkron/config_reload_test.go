@@ -0,0 +1,70 @@
+package dkron
+
+import "testing"
+
+func TestConfigReloadAppliesReloadableFields(t *testing.T) {
+	c := DefaultConfig()
+	c.MailHost = "old.example.com"
+	c.LogLevel = "info"
+
+	newConfig := DefaultConfig()
+	newConfig.MailHost = "new.example.com"
+	newConfig.LogLevel = "debug"
+
+	if err := c.Reload(newConfig); err != nil {
+		t.Fatalf("Reload returned unexpected error: %v", err)
+	}
+
+	if c.MailHost != "new.example.com" {
+		t.Errorf("MailHost = %q, want %q", c.MailHost, "new.example.com")
+	}
+	if c.LogLevel != "debug" {
+		t.Errorf("LogLevel = %q, want %q", c.LogLevel, "debug")
+	}
+}
+
+func TestConfigReloadRejectsInvalidFieldButAppliesOthers(t *testing.T) {
+	c := DefaultConfig()
+	c.LogLevel = "info"
+	c.MailHost = "old.example.com"
+
+	newConfig := DefaultConfig()
+	newConfig.LogLevel = "not-a-level"
+	newConfig.MailHost = "new.example.com"
+
+	if err := c.Reload(newConfig); err == nil {
+		t.Fatal("Reload should report an error for the invalid log level")
+	}
+
+	if c.LogLevel != "info" {
+		t.Errorf("LogLevel should keep its previous value after a failed validation, got %q", c.LogLevel)
+	}
+	if c.MailHost != "new.example.com" {
+		t.Errorf("MailHost should still apply even though LogLevel was rejected, got %q", c.MailHost)
+	}
+}
+
+func TestConfigReloadRejectsNilConfig(t *testing.T) {
+	c := DefaultConfig()
+	if err := c.Reload(nil); err == nil {
+		t.Fatal("Reload(nil) should return an error")
+	}
+}
+
+func TestMergeReloadableKeepsFieldsAbsentFromFile(t *testing.T) {
+	c := DefaultConfig()
+	c.MailHost = "live.example.com"
+	c.MailUsername = "liveuser"
+
+	fileConfig := &Config{MailHost: "file.example.com"}
+	present := map[string]bool{"mail-host": true}
+
+	merged := c.mergeReloadable(fileConfig, present)
+
+	if merged.MailHost != "file.example.com" {
+		t.Errorf("MailHost = %q, want %q", merged.MailHost, "file.example.com")
+	}
+	if merged.MailUsername != "liveuser" {
+		t.Errorf("MailUsername should retain the live value, got %q", merged.MailUsername)
+	}
+}
@@ -0,0 +1,133 @@
+package dkron
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qiangyt/dkron/dkron/discovery"
+)
+
+// DiscoverSpec is a single --discover flag value: a provider name plus the
+// key=value arguments it was configured with, e.g. "k8s" and
+// {"label-selector": "app=dkron"} for "--discover k8s=label-selector=app=dkron".
+type DiscoverSpec struct {
+	Provider string
+	Args     map[string]string
+}
+
+// ParseDiscoverSpec parses a single --discover flag value of the form
+// "provider=key1=val1,key2=val2" into a DiscoverSpec. The provider name and
+// its first argument are separated by the first "=", and further arguments
+// are comma-separated key=value pairs.
+func ParseDiscoverSpec(raw string) (DiscoverSpec, error) {
+	provider, rest, ok := strings.Cut(raw, "=")
+	if !ok {
+		return DiscoverSpec{}, fmt.Errorf("discover: %q is not of the form provider=key1=val1,key2=val2", raw)
+	}
+
+	args := map[string]string{}
+	if rest != "" {
+		for _, pair := range strings.Split(rest, ",") {
+			k, v, ok := strings.Cut(pair, "=")
+			if !ok {
+				return DiscoverSpec{}, fmt.Errorf("discover: argument %q is not of the form key=value", pair)
+			}
+			args[k] = v
+		}
+	}
+
+	return DiscoverSpec{Provider: provider, Args: args}, nil
+}
+
+// ParseDiscoverSpecs parses every raw --discover flag value into Discovers.
+func (c *Config) ParseDiscoverSpecs(raw []string) error {
+	specs := make([]DiscoverSpec, 0, len(raw))
+	for _, r := range raw {
+		spec, err := ParseDiscoverSpec(r)
+		if err != nil {
+			return err
+		}
+		specs = append(specs, spec)
+	}
+	c.Discovers = specs
+	return nil
+}
+
+// discoverRetryInterval and discoverMaxAttempts bound the retry/backoff a
+// single slow or flaky discovery provider can impose; they exist so a single
+// unreachable cloud API can't block agent startup indefinitely.
+const (
+	discoverRetryInterval = 2 * time.Second
+	discoverMaxAttempts   = 3
+)
+
+// DiscoverAddresses runs every configured discovery provider concurrently,
+// each on its own goroutine with its own retry/backoff, and returns the
+// union of the addresses they found. A provider that fails every attempt is
+// logged and skipped rather than failing discovery as a whole.
+func (c *Config) DiscoverAddresses(ctx context.Context) ([]string, error) {
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		union = map[string]struct{}{}
+	)
+
+	for _, spec := range c.Discovers {
+		spec := spec
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			provider, err := discovery.New(spec.Provider, spec.Args)
+			if err != nil {
+				log.WithError(err).Errorf("discover: error creating provider %s", spec.Provider)
+				return
+			}
+
+			addrs, err := discoverWithRetry(ctx, provider)
+			if err != nil {
+				log.WithError(err).Errorf("discover: provider %s failed, skipping", spec.Provider)
+				return
+			}
+
+			mu.Lock()
+			for _, addr := range addrs {
+				union[addr] = struct{}{}
+			}
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	addrs := make([]string, 0, len(union))
+	for addr := range union {
+		addrs = append(addrs, addr)
+	}
+	return addrs, nil
+}
+
+func discoverWithRetry(ctx context.Context, provider discovery.Provider) ([]string, error) {
+	var lastErr error
+
+	for attempt := 0; attempt < discoverMaxAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(discoverRetryInterval):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		addrs, err := provider.Addresses(ctx)
+		if err == nil {
+			return addrs, nil
+		}
+		lastErr = err
+	}
+
+	return nil, fmt.Errorf("discover: %s: %w", provider.Name(), lastErr)
+}
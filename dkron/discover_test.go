@@ -0,0 +1,107 @@
+package dkron
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestDiscoverAddressesUnionsProviders(t *testing.T) {
+	c := &Config{
+		Discovers: []DiscoverSpec{
+			{Provider: "mock", Args: map[string]string{"addrs": "10.0.0.1|10.0.0.2"}},
+			{Provider: "mock", Args: map[string]string{"addrs": "10.0.0.2|10.0.0.3"}},
+		},
+	}
+
+	addrs, err := c.DiscoverAddresses(context.Background())
+	if err != nil {
+		t.Fatalf("DiscoverAddresses: %v", err)
+	}
+
+	got := map[string]bool{}
+	for _, a := range addrs {
+		got[a] = true
+	}
+	for _, want := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+		if !got[want] {
+			t.Errorf("union missing %s, got %v", want, addrs)
+		}
+	}
+	if len(got) != 3 {
+		t.Errorf("union has %d unique addresses, want 3 (the address shared by both providers should collapse), got %v", len(got), addrs)
+	}
+}
+
+func TestDiscoverAddressesSkipsFailingProvider(t *testing.T) {
+	c := &Config{
+		Discovers: []DiscoverSpec{
+			{Provider: "does-not-exist"},
+			{Provider: "mock", Args: map[string]string{"addrs": "10.0.0.1"}},
+		},
+	}
+
+	addrs, err := c.DiscoverAddresses(context.Background())
+	if err != nil {
+		t.Fatalf("DiscoverAddresses: %v", err)
+	}
+	if len(addrs) != 1 || addrs[0] != "10.0.0.1" {
+		t.Errorf("addrs = %v, want [10.0.0.1]: an unknown provider should be skipped, not fail discovery as a whole", addrs)
+	}
+}
+
+// flakyProvider fails its first failUntil calls to Addresses, then succeeds,
+// so discoverWithRetry's retry/backoff loop has something to exercise.
+type flakyProvider struct {
+	failUntil int
+	calls     int
+	addrs     []string
+}
+
+func (p *flakyProvider) Name() string { return "flaky" }
+
+func (p *flakyProvider) Addresses(_ context.Context) ([]string, error) {
+	p.calls++
+	if p.calls <= p.failUntil {
+		return nil, errors.New("flaky: simulated failure")
+	}
+	return p.addrs, nil
+}
+
+func TestDiscoverWithRetrySucceedsAfterTransientFailures(t *testing.T) {
+	p := &flakyProvider{failUntil: 1, addrs: []string{"10.0.0.9"}}
+
+	addrs, err := discoverWithRetry(context.Background(), p)
+	if err != nil {
+		t.Fatalf("discoverWithRetry: %v", err)
+	}
+	if len(addrs) != 1 || addrs[0] != "10.0.0.9" {
+		t.Errorf("addrs = %v, want [10.0.0.9]", addrs)
+	}
+	if p.calls != 2 {
+		t.Errorf("calls = %d, want 2 (one failure, then a retry that succeeds)", p.calls)
+	}
+}
+
+func TestDiscoverWithRetryGivesUpAfterMaxAttempts(t *testing.T) {
+	p := &flakyProvider{failUntil: discoverMaxAttempts + 5}
+
+	_, err := discoverWithRetry(context.Background(), p)
+	if err == nil {
+		t.Fatal("discoverWithRetry should return an error once every attempt has failed")
+	}
+	if p.calls != discoverMaxAttempts {
+		t.Errorf("calls = %d, want %d", p.calls, discoverMaxAttempts)
+	}
+}
+
+func TestDiscoverWithRetryRespectsContextCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	p := &flakyProvider{failUntil: discoverMaxAttempts}
+
+	if _, err := discoverWithRetry(ctx, p); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,120 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	compute "google.golang.org/api/compute/v1"
+)
+
+func init() {
+	Register("ec2", newEC2Provider)
+	Register("gce", newGCEProvider)
+}
+
+// ec2Provider discovers peers as EC2 instances matching a "tag:Key=Value"
+// filter, for clusters running on AWS without a load balancer in front.
+type ec2Provider struct {
+	tagKey, tagValue string
+	client           *ec2.EC2
+}
+
+func newEC2Provider(args map[string]string) (Provider, error) {
+	tag := args["tag"]
+	key, value, ok := strings.Cut(tag, "=")
+	if !ok {
+		return nil, fmt.Errorf("discovery(ec2): tag must be key=value, got %q", tag)
+	}
+
+	sess, err := session.NewSession(&aws.Config{Region: aws.String(args["region"])})
+	if err != nil {
+		return nil, fmt.Errorf("discovery(ec2): error creating session: %w", err)
+	}
+
+	return &ec2Provider{tagKey: key, tagValue: value, client: ec2.New(sess)}, nil
+}
+
+func (p *ec2Provider) Name() string { return "ec2" }
+
+func (p *ec2Provider) Addresses(ctx context.Context) ([]string, error) {
+	out, err := p.client.DescribeInstancesWithContext(ctx, &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			{Name: aws.String("tag:" + p.tagKey), Values: []*string{aws.String(p.tagValue)}},
+			{Name: aws.String("instance-state-name"), Values: []*string{aws.String("running")}},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("discovery(ec2): error describing instances: %w", err)
+	}
+
+	var addrs []string
+	for _, reservation := range out.Reservations {
+		for _, instance := range reservation.Instances {
+			if instance.PrivateIpAddress != nil {
+				addrs = append(addrs, *instance.PrivateIpAddress)
+			}
+		}
+	}
+
+	return addrs, nil
+}
+
+// gceProvider discovers peers as GCE instances matching a "label:key=value"
+// filter within a project/zone, the GCP analogue of ec2Provider.
+type gceProvider struct {
+	project, zone, filter string
+	client                *compute.Service
+}
+
+func newGCEProvider(args map[string]string) (Provider, error) {
+	project := args["project"]
+	zone := args["zone"]
+	if project == "" || zone == "" {
+		return nil, fmt.Errorf("discovery(gce): missing required args %q and %q", "project", "zone")
+	}
+
+	label := args["label"]
+	key, value, ok := strings.Cut(label, "=")
+	if !ok {
+		return nil, fmt.Errorf("discovery(gce): label must be key=value, got %q", label)
+	}
+
+	client, err := compute.NewService(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("discovery(gce): error creating client: %w", err)
+	}
+
+	return &gceProvider{
+		project: project,
+		zone:    zone,
+		filter:  fmt.Sprintf("labels.%s=%s", key, value),
+		client:  client,
+	}, nil
+}
+
+func (p *gceProvider) Name() string { return "gce" }
+
+func (p *gceProvider) Addresses(ctx context.Context) ([]string, error) {
+	var addrs []string
+
+	call := p.client.Instances.List(p.project, p.zone).Filter(p.filter).Context(ctx)
+	err := call.Pages(ctx, func(page *compute.InstanceList) error {
+		for _, instance := range page.Items {
+			for _, iface := range instance.NetworkInterfaces {
+				if iface.NetworkIP != "" {
+					addrs = append(addrs, iface.NetworkIP)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("discovery(gce): error listing instances: %w", err)
+	}
+
+	return addrs, nil
+}
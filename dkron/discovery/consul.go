@@ -0,0 +1,63 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+func init() {
+	Register("consul", newConsulProvider)
+}
+
+// consulProvider discovers peers through the Consul service catalog, for
+// clusters that already run a Consul agent for other service discovery.
+type consulProvider struct {
+	service string
+	client  *consulapi.Client
+}
+
+func newConsulProvider(args map[string]string) (Provider, error) {
+	service := args["service"]
+	if service == "" {
+		return nil, fmt.Errorf("discovery(consul): missing required arg %q", "service")
+	}
+
+	config := consulapi.DefaultConfig()
+	if addr, ok := args["addr"]; ok {
+		config.Address = addr
+	}
+	if token, ok := args["token"]; ok {
+		config.Token = token
+	}
+
+	client, err := consulapi.NewClient(config)
+	if err != nil {
+		return nil, fmt.Errorf("discovery(consul): error creating client: %w", err)
+	}
+
+	return &consulProvider{service: service, client: client}, nil
+}
+
+func (p *consulProvider) Name() string { return "consul" }
+
+func (p *consulProvider) Addresses(ctx context.Context) ([]string, error) {
+	entries, _, err := p.client.Health().Service(p.service, "", true, (&consulapi.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("discovery(consul): error querying service %s: %w", p.service, err)
+	}
+
+	addrs := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		addr := entry.Service.Address
+		if addr == "" {
+			addr = entry.Node.Address
+		}
+		addrs = append(addrs, net.JoinHostPort(addr, strconv.Itoa(entry.Service.Port)))
+	}
+
+	return addrs, nil
+}
@@ -0,0 +1,47 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	Register("dns-srv", newDNSSRVProvider)
+}
+
+// dnsSRVProvider discovers peers by querying the SRV records for
+// _dkron._tcp.<domain>, the approach used by clusters that already publish
+// their own service discovery zone instead of relying on mDNS or a cloud API.
+type dnsSRVProvider struct {
+	domain string
+}
+
+func newDNSSRVProvider(args map[string]string) (Provider, error) {
+	domain := args["domain"]
+	if domain == "" {
+		return nil, fmt.Errorf("discovery(dns-srv): missing required arg %q", "domain")
+	}
+	return &dnsSRVProvider{domain: domain}, nil
+}
+
+func (p *dnsSRVProvider) Name() string { return "dns-srv" }
+
+func (p *dnsSRVProvider) Addresses(ctx context.Context) ([]string, error) {
+	resolver := net.DefaultResolver
+
+	_, srvs, err := resolver.LookupSRV(ctx, "dkron", "tcp", p.domain)
+	if err != nil {
+		return nil, fmt.Errorf("discovery(dns-srv): SRV lookup for %s failed: %w", p.domain, err)
+	}
+
+	addrs := make([]string, 0, len(srvs))
+	for _, srv := range srvs {
+		target := strings.TrimSuffix(srv.Target, ".")
+		addrs = append(addrs, net.JoinHostPort(target, strconv.Itoa(int(srv.Port))))
+	}
+
+	return addrs, nil
+}
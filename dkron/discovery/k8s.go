@@ -0,0 +1,68 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func init() {
+	Register("k8s", newK8sProvider)
+}
+
+// k8sProvider discovers peers as pod IPs matching a label selector, for
+// clusters running as a Kubernetes StatefulSet/DaemonSet without a stable
+// headless-service DNS name.
+type k8sProvider struct {
+	namespace string
+	selector  string
+	client    kubernetes.Interface
+}
+
+func newK8sProvider(args map[string]string) (Provider, error) {
+	selector := args["label-selector"]
+	if selector == "" {
+		return nil, fmt.Errorf("discovery(k8s): missing required arg %q", "label-selector")
+	}
+
+	namespace := args["namespace"]
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("discovery(k8s): error loading in-cluster config: %w", err)
+	}
+
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("discovery(k8s): error creating client: %w", err)
+	}
+
+	return &k8sProvider{namespace: namespace, selector: selector, client: client}, nil
+}
+
+func (p *k8sProvider) Name() string { return "k8s" }
+
+func (p *k8sProvider) Addresses(ctx context.Context) ([]string, error) {
+	pods, err := p.client.CoreV1().Pods(p.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: p.selector,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("discovery(k8s): error listing pods: %w", err)
+	}
+
+	addrs := make([]string, 0, len(pods.Items))
+	for _, pod := range pods.Items {
+		if pod.Status.PodIP == "" {
+			continue
+		}
+		addrs = append(addrs, pod.Status.PodIP)
+	}
+
+	return addrs, nil
+}
@@ -0,0 +1,72 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/hashicorp/mdns"
+)
+
+func init() {
+	Register("mdns", newMDNSProvider)
+}
+
+// mdnsProvider discovers peers advertising the configured mDNS service name,
+// which historically was the sole meaning of Config.Discover.
+type mdnsProvider struct {
+	service string
+	timeout time.Duration
+}
+
+func newMDNSProvider(args map[string]string) (Provider, error) {
+	service := args["name"]
+	if service == "" {
+		service = "dkron"
+	}
+
+	timeout := 3 * time.Second
+	if raw, ok := args["timeout"]; ok {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return nil, fmt.Errorf("discovery(mdns): invalid timeout %q: %w", raw, err)
+		}
+		timeout = d
+	}
+
+	return &mdnsProvider{service: service, timeout: timeout}, nil
+}
+
+func (p *mdnsProvider) Name() string { return "mdns" }
+
+func (p *mdnsProvider) Addresses(ctx context.Context) ([]string, error) {
+	entries := make(chan *mdns.ServiceEntry, 16)
+	var addrs []string
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		for entry := range entries {
+			addrs = append(addrs, net.JoinHostPort(entry.AddrV4.String(), fmt.Sprintf("%d", entry.Port)))
+		}
+	}()
+
+	params := mdns.DefaultParams(fmt.Sprintf("_%s._tcp", p.service))
+	params.Entries = entries
+	params.Timeout = p.timeout
+
+	if err := mdns.Query(params); err != nil {
+		close(entries)
+		return nil, fmt.Errorf("discovery(mdns): query failed: %w", err)
+	}
+	close(entries)
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	return addrs, nil
+}
@@ -0,0 +1,31 @@
+package discovery
+
+import (
+	"context"
+	"strings"
+)
+
+func init() {
+	Register("mock", newMockProvider)
+}
+
+// mockProvider returns a fixed, static list of addresses. It exists for
+// tests that exercise discovery wiring without reaching an mDNS segment or a
+// cloud API.
+type mockProvider struct {
+	addrs []string
+}
+
+func newMockProvider(args map[string]string) (Provider, error) {
+	var addrs []string
+	if raw := args["addrs"]; raw != "" {
+		addrs = strings.Split(raw, "|")
+	}
+	return &mockProvider{addrs: addrs}, nil
+}
+
+func (p *mockProvider) Name() string { return "mock" }
+
+func (p *mockProvider) Addresses(_ context.Context) ([]string, error) {
+	return p.addrs, nil
+}
@@ -0,0 +1,47 @@
+// Package discovery implements pluggable cluster membership discovery for
+// dkron agents. Each Provider resolves a list of addresses an agent should
+// attempt to Serf-join on startup, from some external source (mDNS, DNS,
+// a cloud API, ...); the caller is expected to union the results of every
+// configured provider before joining.
+package discovery
+
+import (
+	"context"
+	"fmt"
+)
+
+// Provider discovers cluster member addresses from an external source so the
+// agent can seed its initial Serf join, instead of requiring every address
+// to be listed explicitly via --join.
+type Provider interface {
+	// Name returns the provider's registration name, e.g. "mdns" or "k8s".
+	Name() string
+	// Addresses returns the addresses currently known to the provider.
+	Addresses(ctx context.Context) ([]string, error)
+}
+
+// Factory builds a Provider from the key=value arguments parsed out of a
+// --discover provider=key1=val1,key2=val2 flag.
+type Factory func(args map[string]string) (Provider, error)
+
+var factories = map[string]Factory{}
+
+// Register adds factory under name, so it can be selected by name from a
+// --discover flag. It panics on duplicate registration, since that indicates
+// a programming error (two providers fighting over the same name) rather
+// than a runtime condition callers should handle.
+func Register(name string, factory Factory) {
+	if _, ok := factories[name]; ok {
+		panic(fmt.Sprintf("discovery: provider %q already registered", name))
+	}
+	factories[name] = factory
+}
+
+// New looks up the Factory registered under name and invokes it with args.
+func New(name string, args map[string]string) (Provider, error) {
+	factory, ok := factories[name]
+	if !ok {
+		return nil, fmt.Errorf("discovery: unknown provider %q", name)
+	}
+	return factory(args)
+}
@@ -0,0 +1,176 @@
+package dkron
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// SendAsMetadataKey is the job metadata key that, when present, overrides
+// the notification's SMTP "From" address. It is the analogue of a catch-all
+// mailbox replying from arbitrary addresses: the mailbox configured via
+// MailUsername/MailPassword keeps authenticating, but the envelope and
+// header From become the job-supplied address, letting per-tenant jobs send
+// notifications from tenant-branded addresses.
+const SendAsMetadataKey = "sendas"
+
+// MailNotifier sends job notification emails using the SMTP settings in
+// Config, negotiating STARTTLS and AUTH as configured.
+type MailNotifier struct {
+	config *Config
+}
+
+// NewMailNotifier creates a MailNotifier bound to config.
+func NewMailNotifier(config *Config) *MailNotifier {
+	return &MailNotifier{config: config}
+}
+
+// Send delivers subject/body to to, using sendAs as the From address if set,
+// falling back to config.MailFrom otherwise. The mailbox in
+// config.MailUsername is always used to authenticate, regardless of sendAs.
+// All of config's mail settings are read once, up front, via
+// Config.mailSettings, so a concurrent SIGHUP reload can't change them out
+// from under a Send already in flight.
+func (n *MailNotifier) Send(to []string, sendAs, subject, body string) error {
+	s := n.config.mailSettings()
+
+	from := s.from
+	if sendAs != "" {
+		from = sendAs
+	}
+
+	addr := net.JoinHostPort(s.host, strconv.Itoa(int(s.port)))
+
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s",
+		from, strings.Join(to, ", "), subject, body)
+
+	conn, err := s.dial(addr)
+	if err != nil {
+		return fmt.Errorf("mail: error connecting to %s: %w", addr, err)
+	}
+
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		return fmt.Errorf("mail: error creating client: %w", err)
+	}
+	defer client.Close()
+
+	if s.useStartTLS && !s.useTLS {
+		tlsConfig, err := s.tlsConfig()
+		if err != nil {
+			return err
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("mail: STARTTLS failed: %w", err)
+		}
+	}
+
+	auth, err := s.auth()
+	if err != nil {
+		return err
+	}
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("mail: AUTH failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(from); err != nil {
+		return fmt.Errorf("mail: MAIL FROM failed: %w", err)
+	}
+	for _, addr := range to {
+		if err := client.Rcpt(addr); err != nil {
+			return fmt.Errorf("mail: RCPT TO %s failed: %w", addr, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("mail: DATA failed: %w", err)
+	}
+	if _, err := w.Write([]byte(msg)); err != nil {
+		return fmt.Errorf("mail: error writing message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("mail: error closing message: %w", err)
+	}
+
+	return client.Quit()
+}
+
+func (s mailSettings) dial(addr string) (net.Conn, error) {
+	if s.useTLS {
+		tlsConfig, err := s.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		return tls.Dial("tcp", addr, tlsConfig)
+	}
+	return net.Dial("tcp", addr)
+}
+
+func (s mailSettings) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         s.host,
+		InsecureSkipVerify: s.skipVerify,
+	}
+
+	if s.rootCAFile != "" {
+		caCert, err := os.ReadFile(s.rootCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("mail: error reading mail-root-ca-file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("mail: no certificates found in %s", s.rootCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
+
+// auth builds the smtp.Auth for s.authMechanism, or nil for "none".
+func (s mailSettings) auth() (smtp.Auth, error) {
+	switch strings.ToLower(s.authMechanism) {
+	case "", "plain":
+		return smtp.PlainAuth("", s.username, s.password, s.host), nil
+	case "login":
+		return &loginAuth{username: s.username, password: s.password}, nil
+	case "cram-md5":
+		return smtp.CRAMMD5Auth(s.username, s.password), nil
+	case "none":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("mail: unknown mail-auth-mechanism %q", s.authMechanism)
+	}
+}
+
+// loginAuth implements the AUTH LOGIN mechanism, which net/smtp does not
+// provide out of the box but which many hardened relays still require.
+type loginAuth struct {
+	username, password string
+}
+
+func (a *loginAuth) Start(_ *smtp.ServerInfo) (string, []byte, error) {
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	switch strings.ToLower(string(fromServer)) {
+	case "username:":
+		return []byte(a.username), nil
+	case "password:":
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("mail: unexpected AUTH LOGIN prompt %q", fromServer)
+	}
+}
@@ -0,0 +1,239 @@
+package dkron
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fakeSMTPResult is what fakeSMTPServer observed during a single session,
+// handed back over a channel once the client hangs up.
+type fakeSMTPResult struct {
+	mailFrom         string
+	authUser         string
+	authPass         string
+	sawAuthMechanism string
+	err              error
+}
+
+// startFakeSMTPServer starts a minimal, single-session SMTP server good
+// enough to drive MailNotifier.Send's AUTH LOGIN and MAIL FROM negotiation,
+// standing in for the real SMTP relay Send talks to in production. It
+// accepts exactly one connection and sends the observed result on the
+// returned channel once that connection closes.
+func startFakeSMTPServer(t *testing.T) (addr string, results <-chan fakeSMTPResult) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	ch := make(chan fakeSMTPResult, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			ch <- fakeSMTPResult{err: err}
+			return
+		}
+		defer conn.Close()
+		ch <- serveFakeSMTP(conn)
+	}()
+
+	return ln.Addr().String(), ch
+}
+
+func serveFakeSMTP(conn net.Conn) fakeSMTPResult {
+	r := bufio.NewReader(conn)
+	var result fakeSMTPResult
+
+	fmt.Fprint(conn, "220 fake.smtp ready\r\n")
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			result.err = err
+			return result
+		}
+		line = strings.TrimRight(line, "\r\n")
+		upper := strings.ToUpper(line)
+
+		switch {
+		case strings.HasPrefix(upper, "EHLO"):
+			fmt.Fprint(conn, "250-fake.smtp\r\n250 AUTH LOGIN PLAIN CRAM-MD5\r\n")
+		case strings.HasPrefix(upper, "AUTH LOGIN"):
+			result.sawAuthMechanism = "login"
+
+			fmt.Fprintf(conn, "334 %s\r\n", base64.StdEncoding.EncodeToString([]byte("Username:")))
+			userLine, err := r.ReadString('\n')
+			if err != nil {
+				result.err = err
+				return result
+			}
+			if userB, err := base64.StdEncoding.DecodeString(strings.TrimRight(userLine, "\r\n")); err == nil {
+				result.authUser = string(userB)
+			}
+
+			fmt.Fprintf(conn, "334 %s\r\n", base64.StdEncoding.EncodeToString([]byte("Password:")))
+			passLine, err := r.ReadString('\n')
+			if err != nil {
+				result.err = err
+				return result
+			}
+			if passB, err := base64.StdEncoding.DecodeString(strings.TrimRight(passLine, "\r\n")); err == nil {
+				result.authPass = string(passB)
+			}
+
+			fmt.Fprint(conn, "235 authenticated\r\n")
+		case strings.HasPrefix(upper, "MAIL FROM:"):
+			result.mailFrom = angleAddr(line)
+			fmt.Fprint(conn, "250 OK\r\n")
+		case strings.HasPrefix(upper, "RCPT TO:"):
+			fmt.Fprint(conn, "250 OK\r\n")
+		case upper == "DATA":
+			fmt.Fprint(conn, "354 go ahead\r\n")
+			for {
+				dataLine, err := r.ReadString('\n')
+				if err != nil {
+					result.err = err
+					return result
+				}
+				if strings.TrimRight(dataLine, "\r\n") == "." {
+					break
+				}
+			}
+			fmt.Fprint(conn, "250 OK\r\n")
+		case upper == "QUIT":
+			fmt.Fprint(conn, "221 bye\r\n")
+			return result
+		default:
+			fmt.Fprint(conn, "500 unrecognized command\r\n")
+		}
+	}
+}
+
+// angleAddr extracts the address between "<" and ">" out of a command line
+// like "MAIL FROM:<user@example.com>".
+func angleAddr(line string) string {
+	start := strings.Index(line, "<")
+	end := strings.LastIndex(line, ">")
+	if start == -1 || end == -1 || end <= start {
+		return line
+	}
+	return line[start+1 : end]
+}
+
+func mailNotifierFor(t *testing.T, addr string, config *Config) *MailNotifier {
+	t.Helper()
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		t.Fatalf("SplitHostPort: %v", err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		t.Fatalf("Atoi: %v", err)
+	}
+	config.MailHost = host
+	config.MailPort = uint16(port)
+	return NewMailNotifier(config)
+}
+
+func TestMailNotifierSendUsesSendAsOverMailFrom(t *testing.T) {
+	addr, results := startFakeSMTPServer(t)
+	n := mailNotifierFor(t, addr, &Config{
+		MailFrom:          "default@example.com",
+		MailUsername:      "bob",
+		MailPassword:      "s3cr3t",
+		MailAuthMechanism: "login",
+	})
+
+	if err := n.Send([]string{"to@example.com"}, "sendas@example.com", "subject", "body"); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	result := <-results
+	if result.err != nil {
+		t.Fatalf("fake SMTP server: %v", result.err)
+	}
+	if result.mailFrom != "sendas@example.com" {
+		t.Errorf("MAIL FROM = %q, want %q", result.mailFrom, "sendas@example.com")
+	}
+	if result.authUser != "bob" || result.authPass != "s3cr3t" {
+		t.Errorf("AUTH LOGIN credentials = %q/%q, want %q/%q", result.authUser, result.authPass, "bob", "s3cr3t")
+	}
+}
+
+func TestMailNotifierSendFallsBackToMailFromWhenSendAsEmpty(t *testing.T) {
+	addr, results := startFakeSMTPServer(t)
+	n := mailNotifierFor(t, addr, &Config{
+		MailFrom:          "default@example.com",
+		MailAuthMechanism: "none",
+	})
+
+	if err := n.Send([]string{"to@example.com"}, "", "subject", "body"); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	result := <-results
+	if result.err != nil {
+		t.Fatalf("fake SMTP server: %v", result.err)
+	}
+	if result.mailFrom != "default@example.com" {
+		t.Errorf("MAIL FROM = %q, want %q (sendAs empty should fall back to MailFrom)", result.mailFrom, "default@example.com")
+	}
+	if result.sawAuthMechanism != "" {
+		t.Errorf("AUTH should not have been attempted with mail-auth-mechanism=none, saw %q", result.sawAuthMechanism)
+	}
+}
+
+func TestLoginAuthNegotiatesUsernameThenPassword(t *testing.T) {
+	a := &loginAuth{username: "bob", password: "s3cr3t"}
+
+	proto, resp, err := a.Start(nil)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if proto != "LOGIN" || resp != nil {
+		t.Errorf("Start = %q, %v, want \"LOGIN\", nil", proto, resp)
+	}
+
+	got, err := a.Next([]byte("Username:"), true)
+	if err != nil {
+		t.Fatalf("Next(username prompt): %v", err)
+	}
+	if string(got) != "bob" {
+		t.Errorf("Next(username prompt) = %q, want %q", got, "bob")
+	}
+
+	got, err = a.Next([]byte("Password:"), true)
+	if err != nil {
+		t.Fatalf("Next(password prompt): %v", err)
+	}
+	if string(got) != "s3cr3t" {
+		t.Errorf("Next(password prompt) = %q, want %q", got, "s3cr3t")
+	}
+
+	got, err = a.Next(nil, false)
+	if err != nil || got != nil {
+		t.Errorf("Next(done) = %v, %v, want nil, nil", got, err)
+	}
+}
+
+func TestLoginAuthNextRejectsUnexpectedPrompt(t *testing.T) {
+	a := &loginAuth{username: "bob", password: "s3cr3t"}
+	if _, err := a.Next([]byte("unexpected prompt:"), true); err == nil {
+		t.Fatal("Next should reject a prompt it doesn't recognize")
+	}
+}
+
+func TestMailNotifierAuthUnknownMechanism(t *testing.T) {
+	n := NewMailNotifier(&Config{MailAuthMechanism: "bogus"})
+	if _, err := n.config.mailSettings().auth(); err == nil {
+		t.Fatal("auth() should reject an unknown mail-auth-mechanism")
+	}
+}
@@ -0,0 +1,255 @@
+package dkron
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	flag "github.com/spf13/pflag"
+)
+
+// envPrefix is prepended to every flag's derived environment variable name.
+const envPrefix = "DKRON"
+
+// FlagInfo describes a single auto-registered configuration flag, enough to
+// regenerate documentation or render `dkron agent --help-json` without
+// hand-transcribing every flag's name, type and description.
+type FlagInfo struct {
+	Name        string `json:"name"`
+	EnvVar      string `json:"env"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+}
+
+// registerConfigFlags walks config's fields via reflection, registering a
+// pflag entry for every field tagged `description:"..."`. The flag name
+// comes from the field's `flag` tag if present, else its `mapstructure` tag,
+// else its lowercased field name. This replaces declaring each field three
+// times across Config, DefaultConfig and ConfigFlagSet: adding a field with
+// a description tag is now enough to also get it as a flag.
+//
+// Fields without a description tag (Discovers, Tags, the unexported
+// reload/TLS state) are skipped; Discovers and Tags are registered by hand in
+// ConfigFlagSet as --discover and --tag, since neither maps onto the flag
+// type this registrar would pick for it.
+func registerConfigFlags(cmdFlags *flag.FlagSet, config *Config) []FlagInfo {
+	var infos []FlagInfo
+
+	v := reflect.ValueOf(config).Elem()
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue // unexported
+		}
+
+		description, ok := field.Tag.Lookup("description")
+		if !ok {
+			continue
+		}
+
+		name := flagNameForField(field)
+
+		typeName := registerField(cmdFlags, name, description, v.Field(i))
+
+		infos = append(infos, FlagInfo{
+			Name:        name,
+			EnvVar:      envVarName(name),
+			Type:        typeName,
+			Description: description,
+		})
+	}
+
+	return infos
+}
+
+func envVarName(flagName string) string {
+	return envPrefix + "_" + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// flagNameForField derives a field's flag name: its `flag` tag if present,
+// else its `mapstructure` tag, else its lowercased field name. Shared by
+// registerConfigFlags and populateConfigFromFlags so the two stay in sync.
+func flagNameForField(field reflect.StructField) string {
+	name := field.Tag.Get("flag")
+	if name == "" {
+		name = field.Tag.Get("mapstructure")
+	}
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+	return name
+}
+
+// registerField registers a single pflag entry for value under name, picking
+// the pflag constructor matching value's Go type, and returns a
+// human-readable type name for FlagInfo. It panics on an unsupported field
+// type, since that is a programming error caught the first time the field
+// is exercised rather than a runtime condition.
+func registerField(cmdFlags *flag.FlagSet, name, description string, value reflect.Value) string {
+	switch v := value.Interface().(type) {
+	case bool:
+		cmdFlags.Bool(name, v, description)
+		return "bool"
+	case int:
+		cmdFlags.Int(name, v, description)
+		return "int"
+	case uint16:
+		cmdFlags.Uint16(name, v, description)
+		return "uint16"
+	case string:
+		cmdFlags.String(name, v, description)
+		return "string"
+	case []string:
+		cmdFlags.StringSlice(name, v, description)
+		return "[]string"
+	case time.Duration:
+		cmdFlags.Duration(name, v, description)
+		return "duration"
+	case map[string]string:
+		cmdFlags.StringToString(name, v, description)
+		return "map[string]string"
+	default:
+		panic(fmt.Sprintf("registerConfigFlags: field for flag %q has unsupported type %T", name, v))
+	}
+}
+
+// populateConfigFromFlags reads back, for every field registerConfigFlags
+// would have registered, that flag's current value (its default if unset,
+// or whatever Parse/ApplyEnvOverrides last set it to) into config. It is the
+// inverse of registerConfigFlags, completing LoadConfig's
+// defaults -> file -> flags -> env resolution for the reflectively-handled
+// fields; the hand-registered flags (--discover, --tag, --help-json) are
+// config's own responsibility, same as in ConfigFlagSet.
+func populateConfigFromFlags(cmdFlags *flag.FlagSet, config *Config) error {
+	v := reflect.ValueOf(config).Elem()
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue // unexported
+		}
+
+		if _, ok := field.Tag.Lookup("description"); !ok {
+			continue
+		}
+
+		if err := setFieldFromFlag(cmdFlags, flagNameForField(field), v.Field(i)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setFieldFromFlag sets field to the current value of the flag registered
+// under name by registerField, picking the pflag getter matching field's Go
+// type. It panics on an unsupported field type for the same reason
+// registerField does: a programming error, not a runtime condition.
+func setFieldFromFlag(cmdFlags *flag.FlagSet, name string, field reflect.Value) error {
+	switch field.Interface().(type) {
+	case bool:
+		val, err := cmdFlags.GetBool(name)
+		if err != nil {
+			return err
+		}
+		field.SetBool(val)
+	case int:
+		val, err := cmdFlags.GetInt(name)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(val))
+	case uint16:
+		val, err := cmdFlags.GetUint16(name)
+		if err != nil {
+			return err
+		}
+		field.SetUint(uint64(val))
+	case string:
+		val, err := cmdFlags.GetString(name)
+		if err != nil {
+			return err
+		}
+		field.SetString(val)
+	case []string:
+		val, err := cmdFlags.GetStringSlice(name)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(val))
+	case time.Duration:
+		val, err := cmdFlags.GetDuration(name)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(val))
+	case map[string]string:
+		val, err := cmdFlags.GetStringToString(name)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(val))
+	default:
+		panic(fmt.Sprintf("populateConfigFromFlags: field for flag %q has unsupported type %T", name, field.Interface()))
+	}
+	return nil
+}
+
+// ApplyEnvOverrides sets, for every flag with a bound environment variable
+// present in the environment, that variable's value onto the flag,
+// regardless of whether the flag was also set on the command line. It is
+// meant to be called after cmdFlags.Parse, completing the
+// defaults -> file -> flags -> env resolution order: env vars are applied
+// last, so they take precedence over an explicit flag.
+func ApplyEnvOverrides(cmdFlags *flag.FlagSet, infos []FlagInfo) error {
+	for _, info := range infos {
+		if cmdFlags.Lookup(info.Name) == nil {
+			continue
+		}
+
+		raw, ok := os.LookupEnv(info.EnvVar)
+		if !ok {
+			continue
+		}
+
+		if err := cmdFlags.Set(info.Name, raw); err != nil {
+			return fmt.Errorf("config: error applying %s=%q to --%s: %w", info.EnvVar, raw, info.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// HelpJSON returns the machine-readable flag documentation served by
+// `dkron agent --help-json`, generated from the same reflection pass that
+// builds the flags themselves so it can never drift from them.
+func HelpJSON() ([]byte, error) {
+	cmdFlags := flag.NewFlagSet("agent flagset", flag.ContinueOnError)
+	infos := registerConfigFlags(cmdFlags, DefaultConfig())
+	return json.MarshalIndent(infos, "", "  ")
+}
+
+// PrintHelpJSON is what wires HelpJSON to `dkron agent --help-json`: callers
+// invoke it right after cmdFlags.Parse, and if --help-json was passed, it
+// writes the flag documentation to stdout and returns true so the caller can
+// exit instead of starting the agent.
+func PrintHelpJSON(cmdFlags *flag.FlagSet) (bool, error) {
+	requested, err := cmdFlags.GetBool("help-json")
+	if err != nil || !requested {
+		return false, nil
+	}
+
+	out, err := HelpJSON()
+	if err != nil {
+		return true, fmt.Errorf("config: error generating --help-json output: %w", err)
+	}
+
+	fmt.Println(string(out))
+	return true, nil
+}
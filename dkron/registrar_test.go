@@ -0,0 +1,41 @@
+package dkron
+
+import "testing"
+
+func TestApplyEnvOverridesTakesPrecedenceOverFlag(t *testing.T) {
+	config := DefaultConfig()
+	cmdFlags, infos := ConfigFlagSet(config)
+
+	if err := cmdFlags.Parse([]string{"--log-level=debug"}); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	t.Setenv("DKRON_LOG_LEVEL", "warn")
+
+	if err := ApplyEnvOverrides(cmdFlags, infos); err != nil {
+		t.Fatalf("ApplyEnvOverrides: %v", err)
+	}
+
+	got, err := cmdFlags.GetString("log-level")
+	if err != nil {
+		t.Fatalf("GetString: %v", err)
+	}
+	if got != "warn" {
+		t.Errorf("log-level = %q, want %q (env should win over an explicit flag)", got, "warn")
+	}
+}
+
+func TestConfigFlagSetTagDefaultFromConfig(t *testing.T) {
+	config := DefaultConfig()
+	config.Tags = map[string]string{"role": "worker"}
+
+	cmdFlags, _ := ConfigFlagSet(config)
+
+	got, err := cmdFlags.GetStringSlice("tag")
+	if err != nil {
+		t.Fatalf("GetStringSlice: %v", err)
+	}
+	if len(got) != 1 || got[0] != "role=worker" {
+		t.Errorf("tag default = %v, want [%q]", got, "role=worker")
+	}
+}
@@ -0,0 +1,62 @@
+package dkron
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// StartReloadHandler installs a signal handler for SIGHUP and SIGUSR2 that,
+// on receipt, reloads TLS certificates via reloadTLS and, if c.ConfigFile is
+// set, also re-reads it and applies its reloadable fields to c via Reload.
+// The handler is installed unconditionally: TLS certificate rotation (see
+// tls.go) does not depend on a config file being configured at all. Closing
+// the returned channel stops the handler; callers can also simply let it run
+// for the lifetime of the process.
+func (c *Config) StartReloadHandler() chan struct{} {
+	done := make(chan struct{})
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP, syscall.SIGUSR2)
+
+	go func() {
+		defer signal.Stop(sig)
+		for {
+			select {
+			case <-sig:
+				c.handleReloadSignal()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return done
+}
+
+func (c *Config) handleReloadSignal() {
+	if c.ConfigFile != "" {
+		log.Infof("config: reloading %s", c.ConfigFile)
+
+		fileConfig, present, err := ReadConfigFile(c.ConfigFile)
+		if err != nil {
+			log.WithError(err).Error("config: reload failed, keeping previous configuration")
+		} else {
+			// fileConfig only has the fields the file actually set; merge it
+			// onto the live config first so a file that only touches e.g.
+			// mail-host doesn't blank out every other reloadable field.
+			merged := c.mergeReloadable(fileConfig, present)
+
+			if err := c.Reload(merged); err != nil {
+				log.WithError(err).Warn("config: reload applied with per-field errors, rejected fields kept their previous value")
+			}
+		}
+	}
+
+	if err := c.reloadTLS(); err != nil {
+		log.WithError(err).Error("config: TLS certificate reload failed, keeping previous certificate")
+		return
+	}
+
+	log.Info("config: reload complete")
+}
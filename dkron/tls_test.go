@@ -0,0 +1,135 @@
+package dkron
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestCert writes a fresh self-signed leaf certificate identified by
+// serial to certFile/keyFile, overwriting whatever was there before. It
+// stands in for the "testtls fixtures" this test would otherwise pull in,
+// since generating a throwaway leaf in-process needs nothing but the
+// standard library.
+func writeTestCert(t *testing.T, certFile, keyFile string, serial int64) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error generating key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "dkron-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("error creating certificate: %v", err)
+	}
+
+	certOut, err := os.Create(certFile)
+	if err != nil {
+		t.Fatalf("error creating cert file: %v", err)
+	}
+	defer certOut.Close()
+	if err := pem.Encode(certOut, &pem.Block{Type: "CERTIFICATE", Bytes: der}); err != nil {
+		t.Fatalf("error writing cert: %v", err)
+	}
+
+	keyOut, err := os.Create(keyFile)
+	if err != nil {
+		t.Fatalf("error creating key file: %v", err)
+	}
+	defer keyOut.Close()
+	if err := pem.Encode(keyOut, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}); err != nil {
+		t.Fatalf("error writing key: %v", err)
+	}
+}
+
+func TestHTTPTLSConfigRotatesOnReloadWithoutDroppingListener(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+
+	writeTestCert(t, certFile, keyFile, 1)
+
+	c := DefaultConfig()
+	c.HTTPCertFile = certFile
+	c.HTTPKeyFile = keyFile
+
+	tlsConfig, err := c.HTTPTLSConfig()
+	if err != nil {
+		t.Fatalf("HTTPTLSConfig: %v", err)
+	}
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", tlsConfig)
+	if err != nil {
+		t.Fatalf("tls.Listen: %v", err)
+	}
+	defer ln.Close()
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(c net.Conn) {
+				defer c.Close()
+				// Complete the handshake server-side before closing, since
+				// tls.Conn negotiates it lazily on first use and closing too
+				// early races the client's tls.Dial handshake.
+				_ = c.(*tls.Conn).Handshake()
+			}(conn)
+		}
+	}()
+
+	dialSerial := func() int64 {
+		conn, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+		if err != nil {
+			t.Fatalf("tls.Dial: %v", err)
+		}
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0].SerialNumber.Int64()
+	}
+
+	if got := dialSerial(); got != 1 {
+		t.Fatalf("serial before rotation = %d, want 1", got)
+	}
+
+	writeTestCert(t, certFile, keyFile, 2)
+	if err := c.reloadTLS(); err != nil {
+		t.Fatalf("reloadTLS: %v", err)
+	}
+
+	if got := dialSerial(); got != 2 {
+		t.Fatalf("serial after rotation = %d, want 2", got)
+	}
+}
+
+func TestTLSConfigNilWhenUnconfigured(t *testing.T) {
+	c := DefaultConfig()
+
+	tlsConfig, err := c.HTTPTLSConfig()
+	if err != nil {
+		t.Fatalf("HTTPTLSConfig: %v", err)
+	}
+	if tlsConfig != nil {
+		t.Fatalf("HTTPTLSConfig should be nil when no cert/key is configured")
+	}
+}